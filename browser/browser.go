@@ -0,0 +1,58 @@
+// Package browser launches the user's default web browser in a way that
+// degrades gracefully when no display server is available, e.g. over SSH.
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoDisplay is returned by Open when no graphical environment was
+// detected (no DISPLAY/WAYLAND_DISPLAY, or no usable Linux/BSD opener).
+var ErrNoDisplay = errors.New("browser: no display server detected")
+
+// Open launches url in the user's default browser.
+//
+// On Windows it shells out to rundll32, which handles URLs containing
+// special characters such as & more reliably than `cmd /c start`. On macOS
+// it uses `open`. On Linux/BSD it first checks for a display server and an
+// `xdg-open` binary, returning ErrNoDisplay if either is missing so callers
+// can fall back instead of launching a command that will never work.
+func Open(url string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		return exec.Command("open", url).Start()
+	default: // "linux", "freebsd", "openbsd", "netbsd"
+		if !hasDisplay() {
+			return ErrNoDisplay
+		}
+		opener, err := exec.LookPath("xdg-open")
+		if err != nil {
+			return ErrNoDisplay
+		}
+		return exec.Command(opener, url).Start()
+	}
+}
+
+// hasDisplay reports whether a graphical session appears to be available,
+// checking both the X11 and Wayland environment variables.
+func hasDisplay() bool {
+	return strings.TrimSpace(os.Getenv("DISPLAY")) != "" || strings.TrimSpace(os.Getenv("WAYLAND_DISPLAY")) != ""
+}
+
+// PrintManualInstructions prints a boxed message telling the user to
+// navigate to url manually. Callers should use it when Open fails or is
+// skipped so the server still ends up reachable.
+func PrintManualInstructions(url string) {
+	msg := fmt.Sprintf("navigate to %s manually", url)
+	border := strings.Repeat("*", len(msg)+4)
+	fmt.Println(border)
+	fmt.Printf("* %s *\n", msg)
+	fmt.Println(border)
+}
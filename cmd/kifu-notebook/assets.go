@@ -0,0 +1,6 @@
+package main
+
+import "net/http"
+
+// Assets serves the notebook's static front-end files.
+var Assets http.FileSystem = http.Dir("static")
@@ -0,0 +1,59 @@
+package main
+
+import "sync"
+
+// revision pairs a saved JKF document with the document it belongs to and
+// the save count it was produced by, so subscribers can filter to the game
+// they care about and tell whether they're already caught up.
+type revision struct {
+	name string
+	rev  int
+	data []byte
+}
+
+// hub fans out JKF saves to every connected /jkf/subscribe client.
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[chan revision]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subscribers: make(map[chan revision]struct{})}
+}
+
+// subscribe registers a new subscriber and returns the channel it will
+// receive published revisions on.
+func (h *hub) subscribe() chan revision {
+	ch := make(chan revision, 1)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes and closes a subscriber's channel.
+func (h *hub) unsubscribe(ch chan revision) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+	close(ch)
+}
+
+// publish broadcasts rev to every current subscriber. Slow subscribers have
+// their pending message replaced rather than blocking the saving client;
+// they'll still catch up since the reconnect handshake carries a revision.
+func (h *hub) publish(rev revision) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- rev:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- rev
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubPublishDeliversToSubscriber(t *testing.T) {
+	h := newHub()
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	want := revision{name: "notebook", rev: 1, data: []byte(`{"a":1}`)}
+	h.publish(want)
+
+	select {
+	case got := <-ch:
+		if got.name != want.name || got.rev != want.rev || string(got.data) != string(want.data) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for published revision")
+	}
+}
+
+func TestHubPublishFansOutToEverySubscriber(t *testing.T) {
+	h := newHub()
+	a := h.subscribe()
+	b := h.subscribe()
+	defer h.unsubscribe(a)
+	defer h.unsubscribe(b)
+
+	h.publish(revision{name: "notebook", rev: 1})
+
+	for _, ch := range []chan revision{a, b} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for fan-out delivery")
+		}
+	}
+}
+
+func TestHubPublishCoalescesForSlowSubscriber(t *testing.T) {
+	h := newHub()
+	ch := h.subscribe()
+	defer h.unsubscribe(ch)
+
+	// Never drain ch: each publish should replace the pending message
+	// instead of blocking, so the second call must return promptly.
+	h.publish(revision{name: "notebook", rev: 1})
+
+	done := make(chan struct{})
+	go func() {
+		h.publish(revision{name: "notebook", rev: 2})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber instead of coalescing")
+	}
+
+	select {
+	case got := <-ch:
+		if got.rev != 2 {
+			t.Fatalf("got revision %d, want the latest (2)", got.rev)
+		}
+	default:
+		t.Fatal("expected the coalesced revision to be queued")
+	}
+}
+
+func TestHubUnsubscribeStopsDelivery(t *testing.T) {
+	h := newHub()
+	ch := h.subscribe()
+	h.unsubscribe(ch)
+
+	h.publish(revision{name: "notebook", rev: 1})
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed after unsubscribe")
+	}
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
-	"runtime"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gbtami/kifu-notebook/browser"
 )
 
+// shutdownGracePeriod bounds how long we wait for in-flight requests (most
+// importantly /jkf saves) to finish once a shutdown signal is received.
+const shutdownGracePeriod = 10 * time.Second
+
 func main() {
 	opts, err := ParseOpts()
 	if err != nil {
@@ -21,14 +29,25 @@ func main() {
 	url := fmt.Sprintf("http://%s/", addr)
 
 	if !exists(opts.path) {
-		log.Printf("Notebook file '%s' not found. It will be created when you save later.", opts.path)
+		log.Printf("Notebook storage '%s' not found. It will be created when you save later.", opts.path)
 	} else {
-		log.Printf("Notebook file '%s' found.", opts.path)
+		log.Printf("Notebook storage '%s' found.", opts.path)
 	}
 
-	server := NewServer(opts.path)
-	http.HandleFunc("/jkf", server.HandleJKF)
-	http.Handle("/", http.FileServer(Assets))
+	storage, err := NewStorage(opts.storage, opts.path)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	server := NewServer(storage)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jkf", server.HandleJKF)
+	mux.HandleFunc("/jkf/subscribe", server.HandleSubscribe)
+	mux.HandleFunc("/jkf/index", server.HandleIndex)
+	mux.HandleFunc("/jkf/revisions", server.HandleRevisions)
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.Handle("/", http.FileServer(Assets))
 
 	// See: http://stackoverflow.com/questions/32738188/go-how-can-i-start-the-browser-after-the-server-started-listening
 	l, err := net.Listen("tcp", addr)
@@ -37,33 +56,75 @@ func main() {
 		os.Exit(1)
 	}
 
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  opts.readTimeout,
+		WriteTimeout: opts.writeTimeout,
+		IdleTimeout:  opts.idleTimeout,
+	}
+
 	log.Println("Kifu Notebook is running at:", url)
 	if !opts.noBrowser {
-		open(url)
+		go openWhenReady(url)
 	}
 
-	if err := http.Serve(l, nil); err != nil {
-		log.Fatal(err)
-		os.Exit(1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(l)
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	case s := <-sig:
+		log.Printf("Received %s, shutting down...", s)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Graceful shutdown failed: %v", err)
+		}
 	}
 }
 
-// See: http://stackoverflow.com/questions/39320371/how-start-web-server-to-open-page-in-browser-in-golang
-func open(url string) error {
-	var cmd string
-	var args []string
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = "cmd"
-		args = []string{"/c", "start"}
-	case "darwin":
-		cmd = "open"
-	default: // "linux", "freebsd", "openbsd", "netbsd"
-		cmd = "xdg-open"
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// openWhenReady waits until the server is actually accepting connections
+// before launching the browser, polling /healthz every 50ms for up to 5s.
+// Launching right after net.Listen succeeds but before the accept loop is
+// running can race on slow machines and hand the browser a connection
+// refused; pprof's webui and similar Go tools poll for the same reason.
+func openWhenReady(url string) {
+	const (
+		pollInterval = 50 * time.Millisecond
+		pollTimeout  = 5 * time.Second
+	)
+
+	client := http.Client{Timeout: pollInterval}
+	deadline := time.Now().Add(pollTimeout)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url + "healthz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				if err := browser.Open(url); err != nil {
+					browser.PrintManualInstructions(url)
+				}
+				return
+			}
+		}
+		time.Sleep(pollInterval)
 	}
-	args = append(args, url)
-	return exec.Command(cmd, args...).Start()
+
+	log.Printf("Timed out waiting for the server to come up; not opening the browser.")
+	browser.PrintManualInstructions(url)
 }
 
 func exists(filepath string) bool {
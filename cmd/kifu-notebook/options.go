@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// defaultTimeout is used for the server's read, write, and idle timeouts
+// unless overridden on the command line.
+const defaultTimeout = 120 * time.Second
+
+// Opts holds the parsed command line configuration for kifu-notebook.
+type Opts struct {
+	host      string
+	port      int
+	path      string
+	noBrowser bool
+	storage   string
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+}
+
+// ParseOpts parses the command line flags and returns the resulting Opts.
+func ParseOpts() (*Opts, error) {
+	host := flag.String("host", "localhost", "host to listen on")
+	port := flag.Int("port", 8080, "port to listen on")
+	noBrowser := flag.Bool("no-browser", false, "do not open the default browser automatically")
+	storage := flag.String("storage", "file", "notebook storage backend: file, dir, or sqlite")
+	readTimeout := flag.Duration("read-timeout", defaultTimeout, "maximum duration for reading the entire request")
+	writeTimeout := flag.Duration("write-timeout", defaultTimeout, "maximum duration before timing out writes of the response")
+	idleTimeout := flag.Duration("idle-timeout", defaultTimeout, "maximum amount of time to wait for the next request on keep-alive connections")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		return nil, fmt.Errorf("usage: kifu-notebook [flags] <notebook.jkf>")
+	}
+
+	return &Opts{
+		host:      *host,
+		port:      *port,
+		path:      args[0],
+		noBrowser: *noBrowser,
+		storage:   *storage,
+
+		readTimeout:  *readTimeout,
+		writeTimeout: *writeTimeout,
+		idleTimeout:  *idleTimeout,
+	}, nil
+}
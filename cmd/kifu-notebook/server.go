@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Kifu Notebook is a local tool; subscribers may come from a board
+	// display on another machine on the LAN, so origin isn't restricted.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// defaultDocument is the document name used when a request doesn't specify
+// one via ?name=. Single-file storage ignores it; dir/sqlite storage use it
+// as the game name, so a bare request still behaves like a single notebook.
+const defaultDocument = "notebook"
+
+// documentName extracts the ?name= query parameter, falling back to
+// defaultDocument so existing single-file-mode clients keep working.
+func documentName(r *http.Request) string {
+	if name := r.URL.Query().Get("name"); name != "" {
+		return name
+	}
+	return defaultDocument
+}
+
+// Server serves and persists the notebook through a Storage backend, and
+// broadcasts every save to subscribers connected on /jkf/subscribe.
+type Server struct {
+	mu      sync.Mutex
+	hub     *hub
+	storage Storage
+	rev     map[string]int
+}
+
+// NewServer creates a Server backed by storage.
+func NewServer(storage Storage) *Server {
+	return &Server{storage: storage, hub: newHub(), rev: make(map[string]int)}
+}
+
+// HandleJKF serves the current notebook on GET and persists the request
+// body as the new notebook on PUT/POST.
+func (s *Server) HandleJKF(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGet(w, r)
+	case http.MethodPut, http.MethodPost:
+		s.handleSave(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	name := documentName(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.storage.Load(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Server) handleSave(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := documentName(r)
+
+	dec := json.NewDecoder(r.Body)
+	var js json.RawMessage
+	if err := dec.Decode(&js); err != nil {
+		http.Error(w, "invalid JKF: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	// Seed the counter before saving, so a history-keeping backend isn't
+	// asked to count the row this save is about to add.
+	base := s.revisionLocked(name)
+	if err := s.storage.Save(name, js); err != nil {
+		s.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.rev[name] = base + 1
+	rev := revision{name: name, rev: s.rev[name], data: js}
+	// Publish while still holding s.mu so two concurrent saves to the same
+	// document can never publish out of order: hub.publish is non-blocking
+	// (it coalesces for slow subscribers), so this can't stall the lock.
+	s.hub.publish(rev)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSubscribe upgrades the connection to a WebSocket and streams the
+// named document (?name=, defaulting like HandleJKF) as a JSON message
+// `{"revision":N,"jkf":...}` every time it's saved. Clients may pass
+// ?since=<revision> on connect to receive the current document immediately
+// if they're behind.
+func (s *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
+	name := documentName(r)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("subscribe: upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := s.hub.subscribe()
+	defer s.hub.unsubscribe(ch)
+
+	// The client never sends application messages on this connection, but
+	// we still need to read from it: that's the only way to notice a
+	// closed or abandoned tab promptly instead of waiting for the next
+	// publish to fail a write, which on an idle notebook could be never.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if since, err := strconv.Atoi(r.URL.Query().Get("since")); err == nil {
+		s.mu.Lock()
+		current := revision{name: name, rev: s.revisionLocked(name), data: s.currentLocked(name)}
+		s.mu.Unlock()
+		if since < current.rev {
+			if err := s.writeRevision(conn, current); err != nil {
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-closed:
+			return
+		case rev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if rev.name != name {
+				continue
+			}
+			if err := s.writeRevision(conn, rev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// revisionLocked returns the in-process revision counter for name, seeding
+// it from storage the first time name is seen in this process. Without
+// this, a process restart would reset every counter to 0 and a client
+// reconnecting with ?since=<a revision from before the restart> would be
+// told it's already caught up when it's actually far behind. Backends that
+// keep real history (SQLite) seed an accurate count; others start at 0,
+// same as before, since they never had a countable history to recover.
+// Callers must hold s.mu.
+func (s *Server) revisionLocked(name string) int {
+	if rev, ok := s.rev[name]; ok {
+		return rev
+	}
+
+	rev := 0
+	if history, err := s.storage.History(name); err == nil {
+		rev = len(history)
+	}
+	s.rev[name] = rev
+	return rev
+}
+
+// currentLocked loads the named document. Callers must hold s.mu.
+func (s *Server) currentLocked(name string) []byte {
+	data, err := s.storage.Load(name)
+	if err != nil {
+		return []byte("{}")
+	}
+	return data
+}
+
+func (s *Server) writeRevision(conn *websocket.Conn, rev revision) error {
+	return conn.WriteJSON(struct {
+		Revision int             `json:"revision"`
+		JKF      json.RawMessage `json:"jkf"`
+	}{Revision: rev.rev, JKF: rev.data})
+}
+
+// HandleIndex lists the names of every document the storage backend knows
+// about, powering directory-mode browsing of multiple games.
+func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	names, err := s.storage.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
+
+// HandleRevisions lists past revisions of the named document (?name=,
+// defaulting like HandleJKF), newest first. Passing ?id=<revision> instead
+// serves that revision's JKF content, for viewing or restoring an old save.
+// It requires a storage backend that retains history, i.e. SQLite.
+func (s *Server) HandleRevisions(w http.ResponseWriter, r *http.Request) {
+	name := documentName(r)
+
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			http.Error(w, "invalid id: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.handleLoadRevision(w, name, id)
+		return
+	}
+
+	revisions, err := s.storage.History(name)
+	if errors.Is(err, ErrHistoryUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (s *Server) handleLoadRevision(w http.ResponseWriter, name string, id int) {
+	data, err := s.storage.LoadRevision(name, id)
+	if errors.Is(err, ErrHistoryUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
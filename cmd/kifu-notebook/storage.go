@@ -0,0 +1,232 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrHistoryUnsupported is returned by Storage.History implementations that
+// don't keep past revisions around, i.e. everything but the SQLite backend.
+var ErrHistoryUnsupported = errors.New("storage: this backend does not keep revision history")
+
+// Revision describes one past save of a document, newest first.
+type Revision struct {
+	ID      int       `json:"id"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Storage is the notebook persistence layer. name identifies a single JKF
+// document; single-document backends ignore it.
+type Storage interface {
+	// Load returns the current JKF document for name.
+	Load(name string) ([]byte, error)
+	// Save persists data as the new current JKF document for name.
+	Save(name string, data []byte) error
+	// List returns the names of every document the backend knows about.
+	List() ([]string, error)
+	// History returns past revisions of name, newest first. Backends that
+	// don't retain history return ErrHistoryUnsupported.
+	History(name string) ([]Revision, error)
+	// LoadRevision returns the JKF document saved as revision id of name,
+	// for viewing or restoring an old save. Backends that don't retain
+	// history return ErrHistoryUnsupported.
+	LoadRevision(name string, id int) ([]byte, error)
+}
+
+// NewStorage builds the Storage backend named by kind. path is the JKF file
+// for "file", the notebook directory for "dir", and the database file for
+// "sqlite".
+func NewStorage(kind, path string) (Storage, error) {
+	switch kind {
+	case "", "file":
+		return &fileStorage{path: path}, nil
+	case "dir":
+		return &dirStorage{dir: path}, nil
+	case "sqlite":
+		return newSQLiteStorage(path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", kind)
+	}
+}
+
+// fileStorage is the original mode: a single JKF file on disk, name is
+// ignored since there is only ever one document.
+type fileStorage struct {
+	path string
+}
+
+func (s *fileStorage) Load(name string) ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []byte("{}"), nil
+	}
+	return data, err
+}
+
+func (s *fileStorage) Save(name string, data []byte) error {
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func (s *fileStorage) List() ([]string, error) {
+	return []string{strings.TrimSuffix(filepath.Base(s.path), filepath.Ext(s.path))}, nil
+}
+
+func (s *fileStorage) History(name string) ([]Revision, error) {
+	return nil, ErrHistoryUnsupported
+}
+
+func (s *fileStorage) LoadRevision(name string, id int) ([]byte, error) {
+	return nil, ErrHistoryUnsupported
+}
+
+// dirStorage keeps each game as its own "<name>.jkf" file under dir.
+type dirStorage struct {
+	dir string
+}
+
+func (s *dirStorage) gamePath(name string) string {
+	return filepath.Join(s.dir, name+".jkf")
+}
+
+func (s *dirStorage) Load(name string) ([]byte, error) {
+	data, err := os.ReadFile(s.gamePath(name))
+	if os.IsNotExist(err) {
+		return []byte("{}"), nil
+	}
+	return data, err
+}
+
+func (s *dirStorage) Save(name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.gamePath(name), data, 0644)
+}
+
+func (s *dirStorage) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".jkf" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), ".jkf"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *dirStorage) History(name string) ([]Revision, error) {
+	return nil, ErrHistoryUnsupported
+}
+
+func (s *dirStorage) LoadRevision(name string, id int) ([]byte, error) {
+	return nil, ErrHistoryUnsupported
+}
+
+// sqliteStorage keeps every save as its own revision row, so nothing is
+// ever overwritten and both undo and version browsing are just queries.
+type sqliteStorage struct {
+	db *sql.DB
+}
+
+func newSQLiteStorage(path string) (*sqliteStorage, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS revisions (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	name      TEXT NOT NULL,
+	data      BLOB NOT NULL,
+	saved_at  DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS revisions_name_idx ON revisions (name, id);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteStorage{db: db}, nil
+}
+
+func (s *sqliteStorage) Load(name string) ([]byte, error) {
+	var data []byte
+	row := s.db.QueryRow(`SELECT data FROM revisions WHERE name = ? ORDER BY id DESC LIMIT 1`, name)
+	if err := row.Scan(&data); err == sql.ErrNoRows {
+		return []byte("{}"), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *sqliteStorage) Save(name string, data []byte) error {
+	_, err := s.db.Exec(`INSERT INTO revisions (name, data, saved_at) VALUES (?, ?, ?)`, name, data, time.Now())
+	return err
+}
+
+func (s *sqliteStorage) List() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT name FROM revisions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *sqliteStorage) History(name string) ([]Revision, error) {
+	rows, err := s.db.Query(`SELECT id, saved_at FROM revisions WHERE name = ? ORDER BY id DESC`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var rev Revision
+		if err := rows.Scan(&rev.ID, &rev.SavedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *sqliteStorage) LoadRevision(name string, id int) ([]byte, error) {
+	var data []byte
+	row := s.db.QueryRow(`SELECT data FROM revisions WHERE name = ? AND id = ?`, name, id)
+	if err := row.Scan(&data); err == sql.ErrNoRows {
+		return nil, fmt.Errorf("storage: no revision %d for %q", id, name)
+	} else if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStorageSaveLoadList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.jkf")
+	s := &fileStorage{path: path}
+
+	if data, err := s.Load("anything"); err != nil || string(data) != "{}" {
+		t.Fatalf("Load before any save = %q, %v; want {}, nil", data, err)
+	}
+
+	want := []byte(`{"header":{}}`)
+	if err := s.Save("ignored-name", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := s.Load("ignored-name")
+	if err != nil || string(got) != string(want) {
+		t.Fatalf("Load = %q, %v; want %q, nil", got, err, want)
+	}
+
+	names, err := s.List()
+	if err != nil || len(names) != 1 || names[0] != "notebook" {
+		t.Fatalf("List = %v, %v; want [notebook], nil", names, err)
+	}
+
+	if _, err := s.History("notebook"); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("History error = %v; want ErrHistoryUnsupported", err)
+	}
+}
+
+func TestDirStorageSaveLoadList(t *testing.T) {
+	s := &dirStorage{dir: filepath.Join(t.TempDir(), "games")}
+
+	if names, err := s.List(); err != nil || len(names) != 0 {
+		t.Fatalf("List on empty dir = %v, %v; want [], nil", names, err)
+	}
+
+	if err := s.Save("game-1", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("Save game-1: %v", err)
+	}
+	if err := s.Save("game-2", []byte(`{"b":2}`)); err != nil {
+		t.Fatalf("Save game-2: %v", err)
+	}
+
+	got, err := s.Load("game-1")
+	if err != nil || string(got) != `{"a":1}` {
+		t.Fatalf("Load game-1 = %q, %v", got, err)
+	}
+
+	names, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{"game-1", "game-2"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("List = %v; want %v", names, want)
+	}
+
+	if _, err := s.History("game-1"); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("History error = %v; want ErrHistoryUnsupported", err)
+	}
+}
+
+func TestDirStorageLoadMissingGameReturnsEmptyDocument(t *testing.T) {
+	s := &dirStorage{dir: t.TempDir()}
+
+	data, err := s.Load("never-saved")
+	if err != nil || string(data) != "{}" {
+		t.Fatalf("Load = %q, %v; want {}, nil", data, err)
+	}
+}
+
+func TestSQLiteStorageSaveLoadListHistory(t *testing.T) {
+	s, err := newSQLiteStorage(filepath.Join(t.TempDir(), "notebook.db"))
+	if err != nil {
+		t.Fatalf("newSQLiteStorage: %v", err)
+	}
+
+	if err := s.Save("game-1", []byte(`{"rev":1}`)); err != nil {
+		t.Fatalf("Save rev 1: %v", err)
+	}
+	if err := s.Save("game-1", []byte(`{"rev":2}`)); err != nil {
+		t.Fatalf("Save rev 2: %v", err)
+	}
+
+	got, err := s.Load("game-1")
+	if err != nil || string(got) != `{"rev":2}` {
+		t.Fatalf("Load = %q, %v; want the latest revision", got, err)
+	}
+
+	names, err := s.List()
+	if err != nil || len(names) != 1 || names[0] != "game-1" {
+		t.Fatalf("List = %v, %v; want [game-1], nil", names, err)
+	}
+
+	revisions, err := s.History("game-1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("History returned %d revisions, want 2", len(revisions))
+	}
+	if revisions[0].ID <= revisions[1].ID {
+		t.Fatalf("History not newest-first: %+v", revisions)
+	}
+
+	oldest := revisions[len(revisions)-1]
+	data, err := s.LoadRevision("game-1", oldest.ID)
+	if err != nil || string(data) != `{"rev":1}` {
+		t.Fatalf("LoadRevision(oldest) = %q, %v; want the first save", data, err)
+	}
+
+	if _, err := s.LoadRevision("game-1", -1); err == nil {
+		t.Fatal("LoadRevision with an unknown id should error")
+	}
+}